@@ -8,105 +8,425 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
 	"syscall"
+	"time"
 
 	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
 	"github.com/mainflux/mainflux"
 	"github.com/mainflux/mainflux/logger"
+	cfgfile "github.com/mainflux/mainflux/pkg/config"
+	"github.com/mainflux/mainflux/pkg/messaging"
+	"github.com/mainflux/mainflux/pkg/messaging/kafka"
+	broker "github.com/mainflux/mainflux/pkg/messaging/nats"
+	"github.com/mainflux/mainflux/pkg/messaging/rabbitmq"
 	thingsapi "github.com/mainflux/mainflux/things/api/grpc"
 	adapter "github.com/mainflux/mainflux/ws"
 	"github.com/mainflux/mainflux/ws/api"
-	"github.com/mainflux/mainflux/ws/nats"
-	broker "github.com/nats-io/go-nats"
+	natsio "github.com/nats-io/go-nats"
+	"github.com/oklog/run"
 	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials"
 )
 
 const (
-	defClientTLS = "false"
-	defCACerts   = ""
-	defPort      = "8180"
-	defLogLevel  = "error"
-	defNatsURL   = broker.DefaultURL
-	defThingsURL = "localhost:8181"
-	envClientTLS = "MF_WS_ADAPTER_CLIENT_TLS"
-	envCACerts   = "MF_WS_ADAPTER_CA_CERTS"
-	envPort      = "MF_WS_ADAPTER_PORT"
-	envLogLevel  = "MF_WS_ADAPTER_LOG_LEVEL"
-	envNatsURL   = "MF_NATS_URL"
-	envThingsURL = "MF_THINGS_URL"
+	defClientTLS     = "false"
+	defCACerts       = ""
+	defPort          = "8180"
+	defLogLevel      = "error"
+	defBrokerType    = "nats"
+	defBrokerURL     = natsio.DefaultURL
+	defThingsURL     = "localhost:8181"
+	defTelemetryPort = "9180"
+	defServerCert    = ""
+	defServerKey     = ""
+	defClientCA      = ""
+	defShutdownTO    = "5s"
+	envClientTLS     = "MF_WS_ADAPTER_CLIENT_TLS"
+	envCACerts       = "MF_WS_ADAPTER_CA_CERTS"
+	envPort          = "MF_WS_ADAPTER_PORT"
+	envLogLevel      = "MF_WS_ADAPTER_LOG_LEVEL"
+	envBrokerType    = "MF_BROKER_TYPE"
+	envBrokerURL     = "MF_BROKER_URL"
+	envThingsURL     = "MF_THINGS_URL"
+	envTelemetryPort = "MF_WS_ADAPTER_TELEMETRY_PORT"
+	envServerCert    = "MF_WS_ADAPTER_SERVER_CERT"
+	envServerKey     = "MF_WS_ADAPTER_SERVER_KEY"
+	envClientCA      = "MF_WS_ADAPTER_CLIENT_CA"
+	envShutdownTO    = "MF_WS_ADAPTER_SHUTDOWN_TIMEOUT"
+
+	brokerNats     = "nats"
+	brokerKafka    = "kafka"
+	brokerRabbitmq = "rabbitmq"
 )
 
 type config struct {
-	clientTLS bool
-	caCerts   string
-	thingsURL string
-	natsURL   string
-	logLevel  string
-	port      string
+	clientTLS     bool
+	caCerts       string
+	thingsURL     string
+	brokerType    string
+	brokerURL     string
+	logLevel      string
+	port          string
+	telemetryPort string
+	serverCert    string
+	serverKey     string
+	clientCA      string
+	shutdownTO    time.Duration
+}
+
+// yamlConfig mirrors config, but grouped into the nested sections a
+// config file exposes to operators: http, grpc.things, nats, tls and log.
+type yamlConfig struct {
+	HTTP struct {
+		Port          string `yaml:"port"`
+		TelemetryPort string `yaml:"telemetry_port"`
+	} `yaml:"http"`
+	GRPC struct {
+		Things struct {
+			URL       string `yaml:"url"`
+			ClientTLS bool   `yaml:"client_tls"`
+			CACerts   string `yaml:"ca_certs"`
+		} `yaml:"things"`
+	} `yaml:"grpc"`
+	Nats struct {
+		Type string `yaml:"type"`
+		URL  string `yaml:"url"`
+	} `yaml:"nats"`
+	TLS struct {
+		ServerCert string `yaml:"server_cert"`
+		ServerKey  string `yaml:"server_key"`
+		ClientCA   string `yaml:"client_ca"`
+	} `yaml:"tls"`
+	Log struct {
+		Level string `yaml:"level"`
+	} `yaml:"log"`
 }
 
 func main() {
-	cfg := loadConfig()
+	configFile := flag.String("config", "", "Path to a YAML config file; MF_* env vars override its values")
+	flag.Parse()
+
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		log.Fatalf(err.Error())
+	}
 
 	logger, err := logger.New(os.Stdout, cfg.logLevel)
 	if err != nil {
 		log.Fatalf(err.Error())
 	}
 
-	nc, err := broker.Connect(cfg.natsURL)
+	pubsub, err := connectBroker(cfg, logger)
 	if err != nil {
-		logger.Error(fmt.Sprintf("Failed to connect to NATS: %s", err))
+		logger.Error(fmt.Sprintf("Failed to connect to message broker: %s", err))
 		os.Exit(1)
 	}
-	defer nc.Close()
+	defer pubsub.Close()
 
 	conn := connectToThings(cfg, logger)
 	defer conn.Close()
 
 	cc := thingsapi.NewClient(conn)
-	pubsub := nats.New(nc)
 	svc := newService(pubsub, logger)
 
-	errs := make(chan error, 2)
+	httpSrv, err := newHTTPServer(cfg, api.MakeHandler(svc, cc, logger))
+	if err != nil {
+		log.Fatalf(err.Error())
+	}
+	telemetrySrv := &http.Server{
+		Addr:    fmt.Sprintf(":%s", cfg.telemetryPort),
+		Handler: telemetryHandler(pubsub, conn),
+	}
 
-	go func() {
-		p := fmt.Sprintf(":%s", cfg.port)
+	var g run.Group
+
+	g.Add(func() error {
 		logger.Info(fmt.Sprintf("WebSocket adapter service started, exposed port %s", cfg.port))
-		errs <- http.ListenAndServe(p, api.MakeHandler(svc, cc, logger))
-	}()
+		return serve(httpSrv, cfg)
+	}, func(error) {
+		shutdown(httpSrv, pubsub, cfg.shutdownTO, logger)
+	})
+
+	g.Add(func() error {
+		logger.Info(fmt.Sprintf("WebSocket adapter telemetry exposed port %s", cfg.telemetryPort))
+		if err := telemetrySrv.ListenAndServe(); err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	}, func(error) {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.shutdownTO)
+		defer cancel()
+		telemetrySrv.Shutdown(ctx)
+	})
+
+	cancelSignal := make(chan struct{})
+	g.Add(func() error {
+		c := make(chan os.Signal, 2)
+		signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
+		select {
+		case sig := <-c:
+			return fmt.Errorf("%s", sig)
+		case <-cancelSignal:
+			return nil
+		}
+	}, func(error) {
+		close(cancelSignal)
+	})
 
-	go func() {
-		c := make(chan os.Signal)
-		signal.Notify(c, syscall.SIGINT)
-		errs <- fmt.Errorf("%s", <-c)
-	}()
+	if err := g.Run(); err != nil {
+		logger.Error(fmt.Sprintf("WebSocket adapter terminated: %s", err))
+	}
+}
+
+// shutdown drains active WebSocket sessions and broker subscriptions
+// before the HTTP server and the underlying broker connection are closed,
+// so in-flight clients get a clean close frame instead of a dropped
+// connection.
+func shutdown(httpSrv *http.Server, pubsub messaging.PubSub, timeout time.Duration, logger logger.Logger) {
+	logger.Info("WebSocket adapter shutting down, draining active sessions")
+	api.DrainSessions(timeout)
+
+	if d, ok := pubsub.(messaging.Drainer); ok {
+		if err := d.Drain(); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to drain broker subscriptions: %s", err))
+		}
+	}
 
-	err = <-errs
-	logger.Error(fmt.Sprintf("WebSocket adapter terminated: %s", err))
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := httpSrv.Shutdown(ctx); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to gracefully shut down WebSocket adapter: %s", err))
+	}
 }
 
-func loadConfig() config {
-	tls, err := strconv.ParseBool(mainflux.Env(envClientTLS, defClientTLS))
+// loadConfig builds the adapter configuration. When configFile is set, its
+// nested YAML sections seed the config first; MF_* environment variables
+// are then applied on top, so they always take precedence over the file.
+func loadConfig(configFile string) (config, error) {
+	cfg := config{
+		clientTLS:     defClientTLS == "true",
+		caCerts:       defCACerts,
+		thingsURL:     defThingsURL,
+		brokerType:    defBrokerType,
+		brokerURL:     defBrokerURL,
+		logLevel:      defLogLevel,
+		port:          defPort,
+		telemetryPort: defTelemetryPort,
+		serverCert:    defServerCert,
+		serverKey:     defServerKey,
+		clientCA:      defClientCA,
+	}
+
+	if configFile != "" {
+		var y yamlConfig
+		if err := cfgfile.LoadFile(configFile, &y); err != nil {
+			return config{}, err
+		}
+		applyYAML(&cfg, y)
+	}
+
+	if v, ok := os.LookupEnv(envClientTLS); ok {
+		if tls, err := strconv.ParseBool(v); err == nil {
+			cfg.clientTLS = tls
+		}
+	}
+	if v, ok := os.LookupEnv(envCACerts); ok {
+		cfg.caCerts = v
+	}
+	if v, ok := os.LookupEnv(envThingsURL); ok {
+		cfg.thingsURL = v
+	}
+	if v, ok := os.LookupEnv(envBrokerType); ok {
+		cfg.brokerType = v
+	}
+	if v, ok := os.LookupEnv(envBrokerURL); ok {
+		cfg.brokerURL = v
+	}
+	if v, ok := os.LookupEnv(envLogLevel); ok {
+		cfg.logLevel = v
+	}
+	if v, ok := os.LookupEnv(envPort); ok {
+		cfg.port = v
+	}
+	if v, ok := os.LookupEnv(envTelemetryPort); ok {
+		cfg.telemetryPort = v
+	}
+	if v, ok := os.LookupEnv(envServerCert); ok {
+		cfg.serverCert = v
+	}
+	if v, ok := os.LookupEnv(envServerKey); ok {
+		cfg.serverKey = v
+	}
+	if v, ok := os.LookupEnv(envClientCA); ok {
+		cfg.clientCA = v
+	}
+
+	shutdownTO := defShutdownTO
+	if v, ok := os.LookupEnv(envShutdownTO); ok {
+		shutdownTO = v
+	}
+	d, err := time.ParseDuration(shutdownTO)
 	if err != nil {
-		tls = false
+		d = 5 * time.Second
 	}
+	cfg.shutdownTO = d
 
-	return config{
-		clientTLS: tls,
-		caCerts:   mainflux.Env(envCACerts, defCACerts),
-		thingsURL: mainflux.Env(envThingsURL, defThingsURL),
-		natsURL:   mainflux.Env(envNatsURL, defNatsURL),
-		logLevel:  mainflux.Env(envLogLevel, defLogLevel),
-		port:      mainflux.Env(envPort, defPort),
+	return cfg, nil
+}
+
+// applyYAML copies every non-empty field of a parsed YAML config onto cfg.
+func applyYAML(cfg *config, y yamlConfig) {
+	if y.HTTP.Port != "" {
+		cfg.port = y.HTTP.Port
+	}
+	if y.HTTP.TelemetryPort != "" {
+		cfg.telemetryPort = y.HTTP.TelemetryPort
+	}
+	if y.GRPC.Things.URL != "" {
+		cfg.thingsURL = y.GRPC.Things.URL
+	}
+	cfg.clientTLS = y.GRPC.Things.ClientTLS
+	if y.GRPC.Things.CACerts != "" {
+		cfg.caCerts = y.GRPC.Things.CACerts
+	}
+	if y.Nats.Type != "" {
+		cfg.brokerType = y.Nats.Type
+	}
+	if y.Nats.URL != "" {
+		cfg.brokerURL = y.Nats.URL
+	}
+	if y.TLS.ServerCert != "" {
+		cfg.serverCert = y.TLS.ServerCert
+	}
+	if y.TLS.ServerKey != "" {
+		cfg.serverKey = y.TLS.ServerKey
+	}
+	if y.TLS.ClientCA != "" {
+		cfg.clientCA = y.TLS.ClientCA
+	}
+	if y.Log.Level != "" {
+		cfg.logLevel = y.Log.Level
+	}
+}
+
+// connectBroker selects and dials the message broker backend configured via
+// MF_BROKER_TYPE, so the adapter can be pointed at NATS, Kafka or RabbitMQ
+// without a code change.
+func connectBroker(cfg config, logger logger.Logger) (messaging.PubSub, error) {
+	switch cfg.brokerType {
+	case brokerKafka:
+		return kafka.New(cfg.brokerURL)
+	case brokerRabbitmq:
+		return rabbitmq.New(cfg.brokerURL)
+	case brokerNats:
+		nc, err := natsio.Connect(cfg.brokerURL)
+		if err != nil {
+			return nil, err
+		}
+		return broker.New(nc), nil
+	default:
+		return nil, fmt.Errorf("unknown broker type: %s", cfg.brokerType)
+	}
+}
+
+// newHTTPServer builds the WebSocket listener, configuring it to serve
+// wss:// directly when a server cert/key pair is given, and to require a
+// verified client certificate for mTLS device authentication when a
+// client CA is also configured. A client CA that can't be read or
+// parsed is a misconfiguration, not something to start without: it
+// returns an error rather than silently serving TLS without client
+// authentication.
+func newHTTPServer(cfg config, handler http.Handler) (*http.Server, error) {
+	srv := &http.Server{Addr: fmt.Sprintf(":%s", cfg.port), Handler: handler}
+
+	if cfg.serverCert == "" || cfg.serverKey == "" {
+		return srv, nil
+	}
+
+	srv.TLSConfig = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+		},
 	}
+
+	if cfg.clientCA != "" {
+		capem, err := ioutil.ReadFile(cfg.clientCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA %s: %s", cfg.clientCA, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(capem) {
+			return nil, fmt.Errorf("failed to parse client CA %s", cfg.clientCA)
+		}
+		srv.TLSConfig.ClientCAs = pool
+		srv.TLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return srv, nil
+}
+
+// serve starts the given server, switching to wss:// when a TLS config
+// was configured by newHTTPServer.
+func serve(srv *http.Server, cfg config) error {
+	if cfg.serverCert == "" || cfg.serverKey == "" {
+		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	}
+
+	if err := srv.ListenAndServeTLS(cfg.serverCert, cfg.serverKey); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// telemetryHandler serves Prometheus metrics alongside liveness and
+// readiness probes, so Kubernetes can stop routing traffic to a pod whose
+// broker connection or things-service channel has gone bad.
+func telemetryHandler(pubsub messaging.PubSub, things *grpc.ClientConn) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if hc, ok := pubsub.(messaging.HealthChecker); ok && !hc.Healthy() {
+			http.Error(w, "message broker connection is not healthy", http.StatusServiceUnavailable)
+			return
+		}
+
+		if state := things.GetState(); state == connectivity.TransientFailure {
+			http.Error(w, "things service connection is "+state.String(), http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+
+	return mux
 }
 
 func connectToThings(cfg config, logger logger.Logger) *grpc.ClientConn {
@@ -133,7 +453,7 @@ func connectToThings(cfg config, logger logger.Logger) *grpc.ClientConn {
 	return conn
 }
 
-func newService(pubsub adapter.Service, logger logger.Logger) adapter.Service {
+func newService(pubsub messaging.PubSub, logger logger.Logger) adapter.Service {
 	svc := adapter.New(pubsub)
 	svc = api.LoggingMiddleware(svc, logger)
 	svc = api.MetricsMiddleware(
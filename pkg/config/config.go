@@ -0,0 +1,36 @@
+// Copyright (c) 2018
+// Mainflux
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package config provides a YAML configuration file loader shared by
+// Mainflux's cmd/* binaries, so operators can manage a single declarative
+// config artifact alongside (or instead of) the usual MF_* environment
+// variables.
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadFile decodes the YAML file at path into out. Unknown keys are
+// rejected so typos in a config file fail fast instead of being silently
+// ignored.
+func LoadFile(path string, out interface{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file %s: %s", path, err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(out); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %s", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2018
+// Mainflux
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type httpSection struct {
+	Port string `yaml:"port"`
+}
+
+type testConfig struct {
+	HTTP httpSection `yaml:"http"`
+}
+
+func writeTemp(t *testing.T, contents string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("failed to write temp config: %s", err)
+	}
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	path := writeTemp(t, "http:\n  port: \"8080\"\n")
+
+	var cfg testConfig
+	if err := LoadFile(path, &cfg); err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+	if cfg.HTTP.Port != "8080" {
+		t.Errorf("expected port 8080, got: %s", cfg.HTTP.Port)
+	}
+}
+
+func TestLoadFileUnknownField(t *testing.T) {
+	path := writeTemp(t, "http:\n  port: \"8080\"\n  bogus: true\n")
+
+	var cfg testConfig
+	if err := LoadFile(path, &cfg); err == nil {
+		t.Fatal("expected an error for unknown field, got nil")
+	}
+}
+
+func TestLoadFileMissing(t *testing.T) {
+	var cfg testConfig
+	if err := LoadFile(filepath.Join(t.TempDir(), "missing.yaml"), &cfg); err == nil {
+		t.Fatal("expected an error for missing file, got nil")
+	}
+}
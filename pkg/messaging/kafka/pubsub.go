@@ -0,0 +1,249 @@
+// Copyright (c) 2018
+// Mainflux
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package kafka holds the Kafka implementation of the messaging.PubSub
+// interface.
+package kafka
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/golang/protobuf/proto"
+	"github.com/mainflux/mainflux/pkg/messaging"
+)
+
+const (
+	prefix = "channels"
+
+	// wildcardRescan is how often a wildcard subscription re-lists topics
+	// to pick up ones created after the subscription started. Kafka has
+	// no native hierarchical wildcard, so this polling is the trade-off
+	// for emulating the NATS/RabbitMQ "whole channel" subscription.
+	wildcardRescan = 10 * time.Second
+)
+
+var (
+	_ messaging.PubSub        = (*pubsub)(nil)
+	_ messaging.HealthChecker = (*pubsub)(nil)
+	_ messaging.Drainer       = (*pubsub)(nil)
+)
+
+type pubsub struct {
+	url       string
+	client    sarama.Client
+	producer  sarama.SyncProducer
+	mu        sync.Mutex
+	seq       uint64
+	consumers map[string]*kafkaConsumer
+}
+
+type kafkaConsumer struct {
+	consumer sarama.Consumer
+	cancel   chan bool
+}
+
+// New returns a new Kafka PubSub, connected to the brokers at url.
+func New(url string) (messaging.PubSub, error) {
+	cfg := sarama.NewConfig()
+	cfg.Producer.Return.Successes = true
+
+	client, err := sarama.NewClient([]string{url}, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	producer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return &pubsub{
+		url:       url,
+		client:    client,
+		producer:  producer,
+		consumers: make(map[string]*kafkaConsumer),
+	}, nil
+}
+
+func (ps *pubsub) Publish(topic string, msg messaging.Message) error {
+	data, err := proto.Marshal(&msg)
+	if err != nil {
+		return err
+	}
+
+	if topic == "" {
+		topic = prefix + "." + msg.Channel
+		if msg.Subtopic != "" {
+			topic = topic + "." + msg.Subtopic
+		}
+	}
+
+	_, _, err = ps.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: topic,
+		Value: sarama.ByteEncoder(data),
+	})
+	return err
+}
+
+func (ps *pubsub) Subscribe(topic string, handler messaging.MessageHandler) (string, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	consumer, err := sarama.NewConsumer([]string{ps.url}, sarama.NewConfig())
+	if err != nil {
+		return "", err
+	}
+
+	ps.seq++
+	id := fmt.Sprintf("%s-%d", topic, ps.seq)
+	kc := &kafkaConsumer{consumer: consumer, cancel: make(chan bool)}
+	ps.consumers[id] = kc
+
+	// Kafka topics are exact strings, so the NATS-style `channels.<id>.>`
+	// wildcard this package subscribes with for a whole-channel
+	// subscription has no native equivalent here; resolve it against the
+	// topics that currently exist instead of passing it straight through.
+	if strings.HasSuffix(topic, ".>") {
+		if err := subscribeWildcard(kc, topic, handler); err != nil {
+			consumer.Close()
+			delete(ps.consumers, id)
+			return "", err
+		}
+		return id, nil
+	}
+
+	partition, err := consumer.ConsumePartition(topic, 0, sarama.OffsetNewest)
+	if err != nil {
+		consumer.Close()
+		delete(ps.consumers, id)
+		return "", err
+	}
+
+	go relay(partition, kc.cancel, handler)
+	return id, nil
+}
+
+// subscribeWildcard consumes every existing topic under the `channels.<id>.`
+// prefix, then periodically re-lists topics so ones created after the
+// subscription started are picked up within wildcardRescan.
+func subscribeWildcard(kc *kafkaConsumer, topic string, handler messaging.MessageHandler) error {
+	base := strings.TrimSuffix(topic, ">")
+	matched := make(map[string]bool)
+
+	scan := func() error {
+		topics, err := kc.consumer.Topics()
+		if err != nil {
+			return err
+		}
+		for _, t := range topics {
+			if matched[t] || !strings.HasPrefix(t, base) {
+				continue
+			}
+			partition, err := kc.consumer.ConsumePartition(t, 0, sarama.OffsetNewest)
+			if err != nil {
+				continue
+			}
+			matched[t] = true
+			go relay(partition, kc.cancel, handler)
+		}
+		return nil
+	}
+
+	if err := scan(); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(wildcardRescan)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-kc.cancel:
+				return
+			case <-ticker.C:
+				scan()
+			}
+		}
+	}()
+
+	return nil
+}
+
+func relay(partition sarama.PartitionConsumer, cancel chan bool, handler messaging.MessageHandler) {
+	for {
+		select {
+		case <-cancel:
+			partition.Close()
+			return
+		case m := <-partition.Messages():
+			var msg messaging.Message
+			if err := proto.Unmarshal(m.Value, &msg); err != nil {
+				continue
+			}
+			handler(msg)
+		}
+	}
+}
+
+func (ps *pubsub) Unsubscribe(id string) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	c, ok := ps.consumers[id]
+	if !ok {
+		return nil
+	}
+	close(c.cancel)
+	delete(ps.consumers, id)
+	return c.consumer.Close()
+}
+
+func (ps *pubsub) Close() error {
+	ps.mu.Lock()
+	for id, c := range ps.consumers {
+		close(c.cancel)
+		c.consumer.Close()
+		delete(ps.consumers, id)
+	}
+	ps.mu.Unlock()
+
+	if err := ps.producer.Close(); err != nil {
+		return err
+	}
+	return ps.client.Close()
+}
+
+// Healthy reports whether at least one broker in the cluster is
+// currently reachable.
+func (ps *pubsub) Healthy() bool {
+	for _, b := range ps.client.Brokers() {
+		if ok, _ := b.Connected(); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Drain unsubscribes every active subscription, leaving the underlying
+// client and producer open so any in-flight publishes can still
+// complete.
+func (ps *pubsub) Drain() error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	for id, c := range ps.consumers {
+		close(c.cancel)
+		if err := c.consumer.Close(); err != nil {
+			return err
+		}
+		delete(ps.consumers, id)
+	}
+	return nil
+}
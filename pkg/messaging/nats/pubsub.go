@@ -0,0 +1,114 @@
+// Copyright (c) 2018
+// Mainflux
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package nats holds the NATS implementation of the messaging.PubSub
+// interface.
+package nats
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/mainflux/mainflux/pkg/messaging"
+	broker "github.com/nats-io/go-nats"
+)
+
+const prefix = "channels"
+
+var (
+	_ messaging.PubSub        = (*pubsub)(nil)
+	_ messaging.HealthChecker = (*pubsub)(nil)
+	_ messaging.Drainer       = (*pubsub)(nil)
+)
+
+type pubsub struct {
+	conn          *broker.Conn
+	mu            sync.Mutex
+	seq           uint64
+	subscriptions map[string]*broker.Subscription
+}
+
+// New returns a new NATS PubSub, backed by the given connection.
+func New(conn *broker.Conn) messaging.PubSub {
+	return &pubsub{
+		conn:          conn,
+		subscriptions: make(map[string]*broker.Subscription),
+	}
+}
+
+func (ps *pubsub) Publish(topic string, msg messaging.Message) error {
+	data, err := encode(msg)
+	if err != nil {
+		return err
+	}
+
+	subject := prefix + "." + msg.Channel
+	if msg.Subtopic != "" {
+		subject = subject + "." + msg.Subtopic
+	}
+	if topic != "" {
+		subject = topic
+	}
+
+	return ps.conn.Publish(subject, data)
+}
+
+func (ps *pubsub) Subscribe(topic string, handler messaging.MessageHandler) (string, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	sub, err := ps.conn.Subscribe(topic, func(m *broker.Msg) {
+		msg, err := decode(m.Data)
+		if err != nil {
+			return
+		}
+		handler(msg)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	ps.seq++
+	id := fmt.Sprintf("%s-%d", topic, ps.seq)
+	ps.subscriptions[id] = sub
+	return id, nil
+}
+
+func (ps *pubsub) Unsubscribe(id string) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	sub, ok := ps.subscriptions[id]
+	if !ok {
+		return nil
+	}
+	delete(ps.subscriptions, id)
+	return sub.Unsubscribe()
+}
+
+func (ps *pubsub) Close() error {
+	ps.conn.Close()
+	return nil
+}
+
+// Healthy reports whether the underlying NATS connection is connected.
+func (ps *pubsub) Healthy() bool {
+	return ps.conn.Status() == broker.CONNECTED
+}
+
+// Drain unsubscribes every active subscription, leaving the underlying
+// connection open so any in-flight publishes can still complete.
+func (ps *pubsub) Drain() error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	for id, sub := range ps.subscriptions {
+		if err := sub.Unsubscribe(); err != nil {
+			return err
+		}
+		delete(ps.subscriptions, id)
+	}
+	return nil
+}
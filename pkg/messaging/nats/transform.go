@@ -0,0 +1,23 @@
+// Copyright (c) 2018
+// Mainflux
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package nats
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/mainflux/mainflux/pkg/messaging"
+)
+
+func encode(msg messaging.Message) ([]byte, error) {
+	return proto.Marshal(&msg)
+}
+
+func decode(data []byte) (messaging.Message, error) {
+	var msg messaging.Message
+	if err := proto.Unmarshal(data, &msg); err != nil {
+		return messaging.Message{}, err
+	}
+	return msg, nil
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2018
+// Mainflux
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package messaging contains the domain concept definitions needed to
+// support Mainflux services functionality.
+//
+// This package defines the interfaces required to provide transport of
+// message between two points, be it service or microservice. It's based
+// on four abstractions:
+//
+//  1. publisher
+//  2. subscriber
+//  3. pubsub
+//  4. message
+package messaging
+
+// SubjectAllChannels represents subject to subscribe for all the channels.
+const SubjectAllChannels = "channels.>"
+
+// Publisher specifies a message publishing API.
+type Publisher interface {
+	// Publish publishes message to the stream.
+	Publish(topic string, msg Message) error
+
+	// Close gracefully closes message publisher's connection.
+	Close() error
+}
+
+// MessageHandler represents a message handler for Subscriber.
+type MessageHandler func(msg Message) error
+
+// Subscriber specifies a message subscription API.
+type Subscriber interface {
+	// Subscribe subscribes to the message stream and consumes messages,
+	// returning an opaque subscription id. Multiple independent
+	// subscribers can subscribe to the same topic; the id disambiguates
+	// one subscription from another so Unsubscribe tears down only the
+	// one it was given, never a different subscriber's.
+	Subscribe(topic string, handler MessageHandler) (string, error)
+
+	// Unsubscribe unsubscribes the subscription identified by id,
+	// previously returned from Subscribe, and stops consuming messages
+	// on it.
+	Unsubscribe(id string) error
+
+	// Close gracefully closes message subscriber's connection.
+	Close() error
+}
+
+// PubSub represents aggregation interface for publisher and subscriber.
+type PubSub interface {
+	Publisher
+	Subscriber
+}
+
+// HealthChecker is implemented by PubSub backends that can report the
+// liveness of their underlying broker connection, so that callers can
+// expose it on a readiness probe.
+type HealthChecker interface {
+	// Healthy reports whether the broker connection is currently usable.
+	Healthy() bool
+}
+
+// Drainer is implemented by PubSub backends that can unsubscribe every
+// active subscription at once, so callers can stop consuming cleanly
+// before closing the underlying broker connection.
+type Drainer interface {
+	// Drain unsubscribes every active subscription.
+	Drain() error
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2018
+// Mainflux
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package rabbitmq holds the RabbitMQ implementation of the
+// messaging.PubSub interface.
+package rabbitmq
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/mainflux/mainflux/pkg/messaging"
+	amqp "github.com/streadway/amqp"
+)
+
+const (
+	exchange = "mainflux"
+	prefix   = "channels"
+)
+
+var (
+	_ messaging.PubSub        = (*pubsub)(nil)
+	_ messaging.HealthChecker = (*pubsub)(nil)
+	_ messaging.Drainer       = (*pubsub)(nil)
+)
+
+type pubsub struct {
+	conn   *amqp.Connection
+	ch     *amqp.Channel
+	mu     sync.Mutex
+	seq    uint64
+	queues map[string]string
+}
+
+// New returns a new RabbitMQ PubSub, connected to the broker at url.
+func New(url string) (messaging.PubSub, error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err := ch.ExchangeDeclare(exchange, "topic", true, false, false, false, nil); err != nil {
+		ch.Close()
+		conn.Close()
+		return nil, err
+	}
+
+	return &pubsub{
+		conn:   conn,
+		ch:     ch,
+		queues: make(map[string]string),
+	}, nil
+}
+
+func (ps *pubsub) Publish(topic string, msg messaging.Message) error {
+	data, err := proto.Marshal(&msg)
+	if err != nil {
+		return err
+	}
+
+	if topic == "" {
+		topic = prefix + "." + msg.Channel
+		if msg.Subtopic != "" {
+			topic = topic + "." + msg.Subtopic
+		}
+	}
+
+	return ps.ch.Publish(exchange, topic, false, false, amqp.Publishing{
+		ContentType: "application/octet-stream",
+		Body:        data,
+	})
+}
+
+func (ps *pubsub) Subscribe(topic string, handler messaging.MessageHandler) (string, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	q, err := ps.ch.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if err := ps.ch.QueueBind(q.Name, bindingKey(topic), exchange, false, nil); err != nil {
+		return "", err
+	}
+
+	msgs, err := ps.ch.Consume(q.Name, "", true, false, false, false, nil)
+	if err != nil {
+		return "", err
+	}
+
+	go func() {
+		for d := range msgs {
+			var msg messaging.Message
+			if err := proto.Unmarshal(d.Body, &msg); err != nil {
+				continue
+			}
+			handler(msg)
+		}
+	}()
+
+	ps.seq++
+	id := fmt.Sprintf("%s-%d", topic, ps.seq)
+	ps.queues[id] = q.Name
+	return id, nil
+}
+
+func (ps *pubsub) Unsubscribe(id string) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	q, ok := ps.queues[id]
+	if !ok {
+		return nil
+	}
+	delete(ps.queues, id)
+	_, err := ps.ch.QueueDelete(q, false, false, false)
+	return err
+}
+
+// bindingKey translates the NATS-style `>` multi-level wildcard used
+// throughout the messaging package into the AMQP topic exchange
+// equivalent, `#`, so whole-channel subscriptions (no subtopic) actually
+// match routing keys instead of being bound as a literal, never-matching
+// word.
+func bindingKey(topic string) string {
+	if strings.HasSuffix(topic, ".>") {
+		return strings.TrimSuffix(topic, ">") + "#"
+	}
+	return topic
+}
+
+func (ps *pubsub) Close() error {
+	if err := ps.ch.Close(); err != nil {
+		return err
+	}
+	return ps.conn.Close()
+}
+
+// Healthy reports whether the underlying AMQP connection is still open.
+func (ps *pubsub) Healthy() bool {
+	return !ps.conn.IsClosed()
+}
+
+// Drain unsubscribes every active subscription, leaving the underlying
+// connection open so any in-flight publishes can still complete.
+func (ps *pubsub) Drain() error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	for id, q := range ps.queues {
+		if _, err := ps.ch.QueueDelete(q, false, false, false); err != nil {
+			return err
+		}
+		delete(ps.queues, id)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2018
+// Mainflux
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ws
+
+import (
+	"sync"
+
+	"github.com/mainflux/mainflux/pkg/messaging"
+)
+
+// Service specifies web socket service API.
+type Service interface {
+	// Publish publishes the message to the channel identified by its
+	// Channel field.
+	Publish(msg messaging.Message) error
+
+	// Subscribe subscribes message from the channel identified by the
+	// provided id, and optionally narrowed by subtopic, then forwards them
+	// to the given channel.
+	Subscribe(chanID, subtopic string, channel *Channel) error
+
+	// Unsubscribe undoes a previous Subscribe for channel, tearing down
+	// the broker subscription that Subscribe made on its behalf. channel
+	// identifies which subscriber to tear down: two callers subscribed to
+	// the same chanID/subtopic pair are independent subscriptions, and
+	// unsubscribing one must never disturb the other's.
+	Unsubscribe(chanID, subtopic string, channel *Channel) error
+}
+
+// prefix matches messaging.SubjectAllChannels ("channels.>") and every
+// broker backend's own default, so a subscriber using the wildcard
+// actually receives what the adapter publishes.
+const prefix = "channels"
+
+var _ Service = (*adapterService)(nil)
+
+type adapterService struct {
+	pubsub messaging.PubSub
+
+	mu   sync.Mutex
+	subs map[*Channel][]string
+}
+
+// New instantiates the WS adapter implementation.
+func New(pubsub messaging.PubSub) Service {
+	return &adapterService{
+		pubsub: pubsub,
+		subs:   make(map[*Channel][]string),
+	}
+}
+
+func (as *adapterService) Publish(msg messaging.Message) error {
+	subj := prefix + "." + msg.Channel
+	if msg.Subtopic != "" {
+		subj = subj + "." + msg.Subtopic
+	}
+	return as.pubsub.Publish(subj, msg)
+}
+
+func (as *adapterService) Subscribe(chanID, subtopic string, channel *Channel) error {
+	handler := func(msg messaging.Message) error {
+		channel.Messages <- msg
+		return nil
+	}
+
+	subs := subjects(chanID, subtopic)
+	ids := make([]string, 0, len(subs))
+	for _, subj := range subs {
+		id, err := as.pubsub.Subscribe(subj, handler)
+		if err != nil {
+			for _, sid := range ids {
+				as.pubsub.Unsubscribe(sid)
+			}
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	as.mu.Lock()
+	as.subs[channel] = ids
+	as.mu.Unlock()
+	return nil
+}
+
+func (as *adapterService) Unsubscribe(chanID, subtopic string, channel *Channel) error {
+	as.mu.Lock()
+	ids := as.subs[channel]
+	delete(as.subs, channel)
+	as.mu.Unlock()
+
+	var err error
+	for _, id := range ids {
+		if e := as.pubsub.Unsubscribe(id); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// subjects returns the broker subjects a subscriber for chanID/subtopic
+// must listen on. A specific subtopic maps to exactly one subject. With
+// no subtopic the subscriber wants every message on the channel, which
+// spans two disjoint subjects: the bare channel subject, for messages
+// Publish sends with no subtopic of their own, and the `>` wildcard, for
+// messages sent under any subtopic — NATS's `>` requires at least one
+// more token, so it can never match the bare subject by itself.
+func subjects(chanID, subtopic string) []string {
+	subj := prefix + "." + chanID
+	if subtopic == "" {
+		return []string{subj, subj + ".>"}
+	}
+	return []string{subj + "." + subtopic}
+}
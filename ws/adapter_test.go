@@ -0,0 +1,40 @@
+// Copyright (c) 2018
+// Mainflux
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ws
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSubjects(t *testing.T) {
+	cases := []struct {
+		desc     string
+		chanID   string
+		subtopic string
+		want     []string
+	}{
+		{
+			desc:   "no subtopic subscribes to the bare channel and every subtopic under it",
+			chanID: "1",
+			want:   []string{"channels.1", "channels.1.>"},
+		},
+		{
+			desc:     "subtopic narrows the subscription to that subtopic only",
+			chanID:   "1",
+			subtopic: "temperature",
+			want:     []string{"channels.1.temperature"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := subjects(tc.chanID, tc.subtopic); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("subjects(%q, %q) = %v, want %v", tc.chanID, tc.subtopic, got, tc.want)
+			}
+		})
+	}
+}
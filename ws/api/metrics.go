@@ -0,0 +1,59 @@
+// Copyright (c) 2018
+// Mainflux
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/mainflux/mainflux/pkg/messaging"
+	"github.com/mainflux/mainflux/ws"
+)
+
+var _ ws.Service = (*metricsMiddleware)(nil)
+
+type metricsMiddleware struct {
+	counter metrics.Counter
+	latency metrics.Histogram
+	svc     ws.Service
+}
+
+// MetricsMiddleware instruments adapter by tracking request count and
+// latency.
+func MetricsMiddleware(svc ws.Service, counter metrics.Counter, latency metrics.Histogram) ws.Service {
+	return &metricsMiddleware{
+		counter: counter,
+		latency: latency,
+		svc:     svc,
+	}
+}
+
+func (mm *metricsMiddleware) Publish(msg messaging.Message) error {
+	defer func(begin time.Time) {
+		mm.counter.With("method", "publish").Add(1)
+		mm.latency.With("method", "publish").Observe(float64(time.Since(begin).Microseconds()))
+	}(time.Now())
+
+	return mm.svc.Publish(msg)
+}
+
+func (mm *metricsMiddleware) Subscribe(chanID, subtopic string, channel *ws.Channel) error {
+	defer func(begin time.Time) {
+		mm.counter.With("method", "subscribe").Add(1)
+		mm.latency.With("method", "subscribe").Observe(float64(time.Since(begin).Microseconds()))
+	}(time.Now())
+
+	return mm.svc.Subscribe(chanID, subtopic, channel)
+}
+
+func (mm *metricsMiddleware) Unsubscribe(chanID, subtopic string, channel *ws.Channel) error {
+	defer func(begin time.Time) {
+		mm.counter.With("method", "unsubscribe").Add(1)
+		mm.latency.With("method", "unsubscribe").Observe(float64(time.Since(begin).Microseconds()))
+	}(time.Now())
+
+	return mm.svc.Unsubscribe(chanID, subtopic, channel)
+}
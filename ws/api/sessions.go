@@ -0,0 +1,64 @@
+// Copyright (c) 2018
+// Mainflux
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// sessions tracks every currently open WebSocket connection, so that they
+// can be drained with a close frame on shutdown instead of being dropped.
+var sessions = struct {
+	mu    sync.Mutex
+	conns map[*websocket.Conn]struct{}
+}{conns: make(map[*websocket.Conn]struct{})}
+
+func addSession(conn *websocket.Conn) {
+	sessions.mu.Lock()
+	defer sessions.mu.Unlock()
+	sessions.conns[conn] = struct{}{}
+}
+
+func removeSession(conn *websocket.Conn) {
+	sessions.mu.Lock()
+	defer sessions.mu.Unlock()
+	delete(sessions.conns, conn)
+}
+
+// DrainSessions sends a close frame to every open WebSocket connection and
+// waits up to timeout for clients to acknowledge before the listener is
+// torn down.
+func DrainSessions(timeout time.Duration) {
+	sessions.mu.Lock()
+	conns := make([]*websocket.Conn, 0, len(sessions.conns))
+	for conn := range sessions.conns {
+		conns = append(conns, conn)
+	}
+	sessions.mu.Unlock()
+
+	if len(conns) == 0 {
+		return
+	}
+
+	deadline := time.Now().Add(timeout)
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server is shutting down")
+	for _, conn := range conns {
+		conn.WriteControl(websocket.CloseMessage, msg, deadline)
+	}
+
+	for time.Now().Before(deadline) {
+		sessions.mu.Lock()
+		open := len(sessions.conns)
+		sessions.mu.Unlock()
+		if open == 0 {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
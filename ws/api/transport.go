@@ -0,0 +1,138 @@
+// Copyright (c) 2018
+// Mainflux
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/mainflux/mainflux"
+	"github.com/mainflux/mainflux/logger"
+	"github.com/mainflux/mainflux/pkg/messaging"
+	"github.com/mainflux/mainflux/ws"
+)
+
+const protocol = "websocket"
+
+var (
+	upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     func(r *http.Request) bool { return true },
+	}
+
+	// subtopicRegExp validates that a subtopic is a dot-separated list of
+	// alphanumeric segments, mirroring what the NATS subject grammar allows.
+	subtopicRegExp = regexp.MustCompile(`^[a-zA-Z0-9]+(\.[a-zA-Z0-9]+)*$`)
+)
+
+// MakeHandler returns http handler with handshake endpoint.
+func MakeHandler(svc ws.Service, tc mainflux.ThingsServiceClient, logger logger.Logger) http.Handler {
+	mux := mux.NewRouter()
+	mux.HandleFunc("/channels/{id}/messages", handshake(svc, tc, logger)).Methods(http.MethodGet)
+	mux.HandleFunc("/channels/{id}/messages/{subtopic:.+}", handshake(svc, tc, logger)).Methods(http.MethodGet)
+
+	return mux
+}
+
+func handshake(svc ws.Service, tc mainflux.ThingsServiceClient, logger logger.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		chanID := vars["id"]
+		subtopic := vars["subtopic"]
+
+		if subtopic != "" && !subtopicRegExp.MatchString(subtopic) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		thingKey := r.URL.Query().Get("authorization")
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		thingID, err := tc.CanAccessByKey(ctx, &mainflux.AccessByKeyReq{Token: thingKey, ChanID: chanID})
+		if err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			logger.Error("Failed to upgrade connection to websocket: " + err.Error())
+			return
+		}
+
+		channel := ws.NewChannel()
+		if err := svc.Subscribe(chanID, subtopic, channel); err != nil {
+			logger.Error("Failed to subscribe to message broker: " + err.Error())
+			conn.Close()
+			return
+		}
+
+		addSession(conn)
+		go listen(conn, svc, channel, chanID, subtopic, thingID.GetValue(), logger)
+		go forward(conn, channel, logger)
+	}
+}
+
+// listen reads messages sent by the WebSocket client and publishes them to
+// the channel/subtopic pair, tagging each one with the publishing thing's
+// identity rather than the channel it was sent to. Once the client
+// disconnects, it tears down the broker subscription and signals forward
+// to stop, so neither leaks past the connection's lifetime.
+func listen(conn *websocket.Conn, svc ws.Service, channel *ws.Channel, chanID, subtopic, publisher string, logger logger.Logger) {
+	defer removeSession(conn)
+	defer conn.Close()
+	defer channel.Close()
+	defer func() {
+		if err := svc.Unsubscribe(chanID, subtopic, channel); err != nil {
+			logger.Warn("Failed to unsubscribe from channel: " + err.Error())
+		}
+	}()
+
+	for {
+		_, payload, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err) {
+				logger.Warn("Connection closed unexpectedly: " + err.Error())
+			}
+			return
+		}
+
+		msg := messaging.Message{
+			Channel:   chanID,
+			Subtopic:  subtopic,
+			Publisher: publisher,
+			Protocol:  protocol,
+			Payload:   payload,
+			Created:   time.Now().UnixNano(),
+		}
+
+		if err := svc.Publish(msg); err != nil {
+			logger.Warn("Failed to publish message: " + err.Error())
+		}
+	}
+}
+
+// forward relays messages received from the broker down to the WebSocket
+// client until the channel is closed.
+func forward(conn *websocket.Conn, channel *ws.Channel, logger logger.Logger) {
+	for {
+		select {
+		case msg := <-channel.Messages:
+			if err := conn.WriteMessage(websocket.BinaryMessage, msg.Payload); err != nil {
+				logger.Warn("Failed to write message to websocket: " + err.Error())
+				return
+			}
+		case <-channel.Closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright (c) 2018
+// Mainflux
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ws
+
+import (
+	"sync"
+
+	"github.com/mainflux/mainflux/pkg/messaging"
+)
+
+// Channel is used for receiving messages published on the channel the
+// WebSocket client is subscribed to.
+type Channel struct {
+	Messages chan messaging.Message
+	Closed   chan bool
+
+	once sync.Once
+}
+
+// NewChannel instantiates empty channel.
+func NewChannel() *Channel {
+	return &Channel{
+		Messages: make(chan messaging.Message),
+		Closed:   make(chan bool),
+	}
+}
+
+// Close channel and stop message forwarding. Closing Closed instead of
+// sending on it means a forward loop that already returned on its own
+// (e.g. a failed write to the client) never leaves Close blocked with
+// no one left to receive; it's also safe to call more than once.
+func (channel *Channel) Close() {
+	channel.once.Do(func() {
+		close(channel.Closed)
+	})
+}